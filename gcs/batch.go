@@ -0,0 +1,57 @@
+package gcs
+
+import "math"
+
+// OptimizeBatch picks a single MatchType strategy for matching one query set
+// of size querySize against every filter in filterSizes, amortizing the cost
+// of preparing the query set across the whole batch instead of paying it
+// once per filter as repeated calls to Optimize would: MatchZip shares its
+// one-time sort of the query set, and MatchBlock shares its one-time query
+// hashmap build. MatchHash's hashmap is built from each filter's own n
+// entries, not the (shared) query set, so that cost cannot be amortized and
+// is charged per filter; only the one-time cost of precomputing the query
+// keys looked up against it is hoisted out. It returns the per-filter cost
+// estimate for the chosen strategy, in filterSizes order; these are the
+// marginal per-filter costs and don't include the batch's shared one-time
+// setup cost.
+//
+// This is meant for callers like a light client doing a rescan, which match
+// the same query set against thousands of block filters back-to-back and
+// can otherwise only reason about the cost of a single (q, n) pair at a
+// time.
+func OptimizeBatch(querySize int, filterSizes []int) (MatchType, []float64) {
+	c := CurrentCosts()
+	Q := float64(querySize)
+	expQ := expQueries(Q)
+
+	onceZip := Q*c.Key + Q*math.Log2(Q)*c.Sort
+	onceHash := expQ * c.Key
+	onceBlock := Q*c.Key + Q*c.Insert
+
+	zipCosts := make([]float64, len(filterSizes))
+	hashCosts := make([]float64, len(filterSizes))
+	blockCosts := make([]float64, len(filterSizes))
+
+	totalZip, totalHash, totalBlock := onceZip, onceHash, onceBlock
+	for i, n := range filterSizes {
+		N := float64(n)
+		expN := expReads(Q, N, expQ)
+
+		zipCosts[i] = (expQ+expN)*c.Comp + expN*c.Read
+		hashCosts[i] = N*(c.Insert+c.Read) + expQ*c.Lookup
+		blockCosts[i] = N*(c.Read+c.Comp) + expN*c.Lookup
+
+		totalZip += zipCosts[i]
+		totalHash += hashCosts[i]
+		totalBlock += blockCosts[i]
+	}
+
+	switch {
+	case totalZip <= totalHash && totalZip <= totalBlock:
+		return MatchZip, zipCosts
+	case totalHash <= totalBlock:
+		return MatchHash, hashCosts
+	default:
+		return MatchBlock, blockCosts
+	}
+}
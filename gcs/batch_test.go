@@ -0,0 +1,75 @@
+package gcs
+
+import "testing"
+
+// TestOptimizeBatch checks that OptimizeBatch picks MatchZip once the batch
+// is large enough to amortize its one-time sort, even though a single
+// (q, n) pair of the same sizes favors MatchHash under Optimize, since
+// Optimize must pay the sort cost against just one filter.
+func TestOptimizeBatch(t *testing.T) {
+	const querySize = 1000
+	const filterSize = 1000
+	const batchLen = 10000
+
+	filterSizes := make([]int, batchLen)
+	for i := range filterSizes {
+		filterSizes[i] = filterSize
+	}
+
+	singleType, _ := Optimize(querySize, filterSize)
+	batchType, costs := OptimizeBatch(querySize, filterSizes)
+
+	if len(costs) != batchLen {
+		t.Fatalf("want %d per-filter costs, got %d", batchLen, len(costs))
+	}
+
+	if batchType != MatchZip {
+		t.Fatalf("want MatchZip to win a %d-filter batch, got %s",
+			batchLen, batchType)
+	}
+
+	if singleType == batchType {
+		t.Fatalf("expected batching to change the winning strategy away "+
+			"from the single-filter choice %s", singleType)
+	}
+}
+
+// TestOptimizeBatchHashVsBlock checks the Hash-vs-Block boundary directly:
+// MatchHash's hashmap is rebuilt from each filter's own entries rather than
+// the shared query set, so it cannot be amortized the way MatchZip's sort or
+// MatchBlock's query-set build can. Against a modest batch of equally-sized
+// filters that difference should be enough to put MatchBlock ahead of
+// MatchHash, even though MatchHash's one-time query-key precompute is
+// cheaper than MatchBlock's one-time query-set build.
+func TestOptimizeBatchHashVsBlock(t *testing.T) {
+	const querySize = 1000
+	const filterSize = 1000
+	const batchLen = 10
+
+	filterSizes := make([]int, batchLen)
+	for i := range filterSizes {
+		filterSizes[i] = filterSize
+	}
+
+	batchType, costs := OptimizeBatch(querySize, filterSizes)
+	if len(costs) != batchLen {
+		t.Fatalf("want %d per-filter costs, got %d", batchLen, len(costs))
+	}
+
+	if batchType != MatchBlock {
+		t.Fatalf("want MatchBlock to win a %d-filter batch, got %s",
+			batchLen, batchType)
+	}
+}
+
+// TestOptimizeBatchSingleFilter checks that a batch of one filter doesn't
+// blow up and returns exactly one cost estimate.
+func TestOptimizeBatchSingleFilter(t *testing.T) {
+	mType, costs := OptimizeBatch(100, []int{1000})
+	if len(costs) != 1 {
+		t.Fatalf("want 1 per-filter cost, got %d", len(costs))
+	}
+	if mType != MatchZip && mType != MatchHash && mType != MatchBlock {
+		t.Fatalf("unexpected MatchType: %s", mType)
+	}
+}
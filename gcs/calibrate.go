@@ -0,0 +1,210 @@
+package gcs
+
+import (
+	"context"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/aead/siphash"
+	"github.com/kkdai/bstream"
+)
+
+// calibrateBatch is the number of elements each calibration workload
+// processes per iteration before its elapsed time is checked against the
+// per-operation budget.
+const calibrateBatch = 4096
+
+// Calibrate measures the relative cost of each operation Optimize's model
+// depends on, by running the same workloads as BenchmarkCostSort,
+// BenchmarkCostInsert, BenchmarkCostLookup, BenchmarkCostComp, BenchmarkCostKey,
+// and BenchmarkCostRead for a bounded duration each, and installs the
+// normalized result via SetCosts so that CurrentCosts reflects the host
+// machine instead of whatever machine the original constants were measured
+// on. It returns ctx.Err() if the context is canceled before calibration
+// completes.
+func Calibrate(ctx context.Context) error {
+	const perOp = 50 * time.Millisecond
+
+	sortNs, err := timeWorkload(ctx, perOp, calibrateSort)
+	if err != nil {
+		return err
+	}
+	readNs, err := timeWorkload(ctx, perOp, calibrateRead)
+	if err != nil {
+		return err
+	}
+	insertNs, err := timeWorkload(ctx, perOp, calibrateInsert)
+	if err != nil {
+		return err
+	}
+	lookupNs, err := timeWorkload(ctx, perOp, calibrateLookup)
+	if err != nil {
+		return err
+	}
+	compNs, err := timeWorkload(ctx, perOp, calibrateComp)
+	if err != nil {
+		return err
+	}
+	keyNs, err := timeWorkload(ctx, perOp, calibrateKey)
+	if err != nil {
+		return err
+	}
+
+	// Normalize so Comp == 1.0, matching the convention of the original
+	// hard-coded constants.
+	SetCosts(Costs{
+		Sort:   sortNs / compNs,
+		Read:   readNs / compNs,
+		Insert: insertNs / compNs,
+		Lookup: lookupNs / compNs,
+		Comp:   1.0,
+		Key:    keyNs / compNs,
+	})
+
+	return nil
+}
+
+// timeWorkload repeatedly runs workload against batches of calibrateBatch
+// elements until budget has elapsed, and returns the measured cost per
+// element in nanoseconds.
+func timeWorkload(ctx context.Context, budget time.Duration, workload func(n int)) (float64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	var ops int64
+	start := time.Now()
+	for time.Since(start) < budget {
+		workload(calibrateBatch)
+		ops += calibrateBatch
+
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+	}
+
+	return float64(time.Since(start).Nanoseconds()) / float64(ops), nil
+}
+
+// calibrateSort mirrors BenchmarkCostSort: sort n random uint64s.
+func calibrateSort(n int) {
+	s := make([]uint64, n)
+	for i := range s {
+		s[i] = rand.Uint64()
+	}
+	sort.Slice(s, func(i, j int) bool { return s[i] < s[j] })
+}
+
+// calibrateInsert mirrors BenchmarkCostInsert: insert n random uint64s into a
+// map.
+func calibrateInsert(n int) {
+	s := make([]uint64, n)
+	for i := range s {
+		s[i] = rand.Uint64()
+	}
+
+	m := make(map[uint64]struct{}, n)
+	for _, v := range s {
+		m[v] = struct{}{}
+	}
+}
+
+// calibrateLookup mirrors BenchmarkCostLookup: look up n random uint64s in a
+// map pre-populated with the same n values.
+func calibrateLookup(n int) {
+	s := make([]uint64, n)
+	for i := range s {
+		s[i] = rand.Uint64()
+	}
+
+	m := make(map[uint64]struct{}, n)
+	for _, v := range s {
+		m[v] = struct{}{}
+	}
+
+	var ok bool
+	for _, v := range s {
+		_, ok = m[v]
+	}
+	_ = ok
+}
+
+// calibrateComp mirrors BenchmarkCostComp: merge-compare two sorted-length-n
+// slices of random uint64s.
+func calibrateComp(n int) {
+	s1 := make([]uint64, n)
+	s2 := make([]uint64, n)
+	for i := range s1 {
+		s1[i] = rand.Uint64()
+		s2[i] = rand.Uint64()
+	}
+
+	var idx1, idx2 int
+	for idx1 < len(s1) && idx2 < len(s2) {
+		if s1[idx1] < s2[idx2] {
+			idx1++
+		} else {
+			idx2++
+		}
+	}
+}
+
+// calibrateKey mirrors BenchmarkCostKey: derive n siphash keys and reduce
+// them into the filter's modulus space.
+func calibrateKey(n int) {
+	data := make([][]byte, n)
+	for i := range data {
+		data[i] = make([]byte, 24)
+	}
+
+	modulusNP := 10000 * uint64(M)
+	key := [KeySize]byte{
+		0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08,
+		0x09, 0x10, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16,
+	}
+
+	nphi := modulusNP >> 32
+	nplo := uint64(uint32(modulusNP))
+
+	var v uint64
+	for _, d := range data {
+		h := siphash.Sum64(d, &key)
+		v = fastReduction(h, nphi, nplo)
+	}
+	_ = v
+}
+
+// calibrateRead mirrors BenchmarkCostRead: stream every entry out of an
+// n-element filter's Golomb-Rice coded list.
+func calibrateRead(n int) {
+	data := make([][]byte, n)
+	for i := range data {
+		data[i] = make([]byte, 24)
+	}
+
+	key := [KeySize]byte{
+		0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08,
+		0x09, 0x10, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16,
+	}
+
+	filter, err := BuildGCSFilter(19, 784931, key, data)
+	if err != nil {
+		return
+	}
+	filterData, err := filter.Bytes()
+	if err != nil {
+		return
+	}
+
+	b := bstream.NewBStreamReader(filterData)
+
+	var value uint64
+	for {
+		delta, err := filter.readFullUint64(b)
+		if err != nil {
+			return
+		}
+		value += delta
+	}
+}
@@ -0,0 +1,59 @@
+package gcs
+
+import (
+	"context"
+	"testing"
+)
+
+// TestCalibrateRestore calibrates the cost constants, snapshots them,
+// perturbs the installed values, restores the snapshot via SetCosts, and
+// verifies Optimize returns the same decisions it did right after
+// calibration.
+func TestCalibrateRestore(t *testing.T) {
+	if err := Calibrate(context.Background()); err != nil {
+		t.Fatalf("Calibrate failed: %v", err)
+	}
+	snapshot := CurrentCosts()
+
+	queryFilterPairs := [][2]int{
+		{1, 1000},
+		{100, 1000},
+		{10000, 1000},
+		{1000000, 10000},
+	}
+
+	wantDecisions := make([]MatchType, len(queryFilterPairs))
+	for i, qn := range queryFilterPairs {
+		mType, _ := Optimize(qn[0], qn[1])
+		wantDecisions[i] = mType
+	}
+
+	SetCosts(Costs{
+		Sort:   snapshot.Sort * 1000,
+		Read:   snapshot.Read * 1000,
+		Insert: snapshot.Insert * 1000,
+		Lookup: snapshot.Lookup * 1000,
+		Comp:   snapshot.Comp * 1000,
+		Key:    snapshot.Key * 1000,
+	})
+
+	SetCosts(snapshot)
+
+	for i, qn := range queryFilterPairs {
+		mType, _ := Optimize(qn[0], qn[1])
+		if mType != wantDecisions[i] {
+			t.Fatalf("q=%d n=%d: want %s after restore, got %s",
+				qn[0], qn[1], wantDecisions[i], mType)
+		}
+	}
+}
+
+// TestCalibrateCanceled verifies Calibrate respects context cancellation.
+func TestCalibrateCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := Calibrate(ctx); err == nil {
+		t.Fatal("expected Calibrate to return an error for a canceled context")
+	}
+}
@@ -0,0 +1,70 @@
+package gcs
+
+import (
+	"io"
+
+	"github.com/aead/siphash"
+	"github.com/kkdai/bstream"
+)
+
+// MatchAnyWithStrategy is a variant of MatchAny that accepts an explicit
+// MatchType override instead of letting the filter pick a strategy via
+// Optimize. It exists primarily so callers can benchmark the strategies
+// against one another on real filters rather than only against the
+// synthetic cost model in Optimize.
+func (f *GCSFilter) MatchAnyWithStrategy(
+	key [KeySize]byte, data [][]byte, mType MatchType) (bool, error) {
+
+	switch mType {
+	case MatchBlock:
+		return f.matchBlockAny(key, data)
+	case MatchHash:
+		return f.matchHash(key, data)
+	default:
+		return f.matchZip(key, data)
+	}
+}
+
+// matchBlockAny implements the MatchBlock strategy: it builds a hash set out
+// of the query items once, then streams every entry in the filter's
+// Golomb-Rice coded list exactly once, probing the set for each decoded
+// value. Unlike MatchZip, which sorts the query set and merges it against
+// the filter stream, MatchBlock never sorts; unlike MatchHash, which builds
+// its hash map from the filter's own entries and looks the query items up
+// in it, MatchBlock hashes only the query set.
+func (f *GCSFilter) matchBlockAny(key [KeySize]byte, data [][]byte) (bool, error) {
+	filterData, err := f.Bytes()
+	if err != nil {
+		return false, err
+	}
+
+	modulusNP := uint64(f.N()) * uint64(M)
+	nphi := modulusNP >> 32
+	nplo := uint64(uint32(modulusNP))
+
+	querySet := make(map[uint64]struct{}, len(data))
+	for _, d := range data {
+		v := siphash.Sum64(d, &key)
+		querySet[fastReduction(v, nphi, nplo)] = struct{}{}
+	}
+
+	br := bstream.NewBStreamReader(filterData)
+
+	var value uint64
+	for i := uint32(0); i < f.N(); i++ {
+		delta, err := f.readFullUint64(br)
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return false, err
+		}
+		value += delta
+
+		if _, ok := querySet[value]; ok {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -1,6 +1,9 @@
 package gcs
 
-import "math"
+import (
+	"math"
+	"sync/atomic"
+)
 
 const M = 784931.0
 
@@ -27,47 +30,107 @@ func (m MatchType) String() string {
 	}
 }
 
-const (
-	CostSort = 130.0
+// Costs holds the relative cost constants Optimize's model is built from.
+// These were originally hard-coded from benchmarks taken on one machine,
+// which silently biased Optimize's decisions on any other CPU. Calibrate
+// measures them for the machine Optimize is actually running on.
+type Costs struct {
+	Sort   float64
+	Read   float64
+	Insert float64
+	Lookup float64
+	Comp   float64
+	Key    float64
+}
 
-	CostRead = 30.0
+// defaultCosts are the original hard-coded constants, kept as the fallback
+// until Calibrate or SetCosts installs a measured set.
+var defaultCosts = Costs{
+	Sort:   130.0,
+	Read:   30.0,
+	Insert: 45.0,
+	Lookup: 30.0,
+	Comp:   1.0,
+	Key:    3.0,
+}
 
-	CostInsert = 45.0
+var currentCosts atomic.Value
 
-	CostLookup = 30.0
+func init() {
+	currentCosts.Store(defaultCosts)
+}
 
-	CostComp = 1.0
+// CurrentCosts returns the cost constants Optimize currently uses.
+func CurrentCosts() Costs {
+	return currentCosts.Load().(Costs)
+}
 
-	CostKey = 3.0
-)
+// SetCosts installs c as the cost constants Optimize uses going forward,
+// replacing the defaults or a prior calibration. It's safe to call while
+// Optimize is running concurrently elsewhere, and is the inverse of
+// CurrentCosts: callers can persist a CurrentCosts snapshot to disk and
+// restore it at startup with SetCosts instead of recalibrating.
+func SetCosts(c Costs) {
+	currentCosts.Store(c)
+}
 
+// Optimize returns the MatchType with the lowest expected cost for matching a
+// query set of size querySize against a filter of size filterSize, along
+// with the ratio of its cost against the runner-up strategy (<= 1, smaller
+// means a more decisive win).
 func Optimize(querySize, filterSize int) (MatchType, float64) {
 	Q := float64(querySize)
 	N := float64(filterSize)
 
+	c := CurrentCosts()
+
 	expQ := expQueries(Q)
 	expN := expReads(Q, N, expQ)
 
-	cZip := costZip(Q, expQ, expN)
-	cHash := costHash(Q, N, expQ)
+	cZip := costZip(c, Q, expQ, expN)
+	cHash := costHash(c, Q, N, expQ)
+	cBlock := costBlock(c, Q, N, expN)
 
-	if cZip < cHash {
-		return MatchZip, cZip / cHash
+	best, bestCost := MatchZip, cZip
+	runnerUp := math.Min(cHash, cBlock)
+
+	if cHash < bestCost {
+		best, bestCost = MatchHash, cHash
+		runnerUp = math.Min(cZip, cBlock)
+	}
+	if cBlock < bestCost {
+		best, bestCost = MatchBlock, cBlock
+		runnerUp = math.Min(cZip, cHash)
 	}
-	return MatchHash, cHash / cZip
+
+	return best, bestCost / runnerUp
+}
+
+func costZip(c Costs, q, expQ, expN float64) float64 {
+	return q*c.Key +
+		q*math.Log2(q)*c.Sort +
+		(expQ+expN)*c.Comp +
+		expN*c.Read
 }
 
-func costZip(q, expQ, expN float64) float64 {
-	return q*CostKey +
-		q*math.Log2(q)*CostSort +
-		(expQ+expN)*CostComp +
-		expN*CostRead
+func costHash(c Costs, q, n, expQ float64) float64 {
+	return n*(c.Insert+c.Read) +
+		expQ*c.Key +
+		expQ*c.Lookup
 }
 
-func costHash(q, n, expQ float64) float64 {
-	return n*(CostInsert+CostRead) +
-		expQ*CostKey +
-		expQ*CostLookup
+// costBlock estimates the cost of the MatchBlock strategy: build a hash set
+// from the q query items once, then stream every one of the filter's n
+// entries, looking each decoded value up in the set. This trades the
+// q*log2(q) sort paid by MatchZip for a build-once-per-filter set, at the
+// cost of reading and comparing every filter entry rather than only the
+// expN entries a merge would touch.
+func costBlock(c Costs, q, n, expN float64) float64 {
+	return n*c.Read +
+		n*c.Comp +
+		q*c.Key +
+		q*c.Insert +
+		expN*c.Lookup
 }
 
 func expQueries(q float64) float64 {
@@ -10,13 +10,19 @@ import (
 	"github.com/kkdai/bstream"
 )
 
+// TestOptimize prints a table of the winning MatchType across a grid of
+// query and filter sizes, so the crossover points between MatchZip,
+// MatchHash, and MatchBlock are visible by inspection.
 func TestOptimize(t *testing.T) {
-	for _, n := range []int{1, 10, 100, 1000, 10000} {
+	filterSizes := []int{1, 10, 100, 1000, 10000}
+	querySizes := []int{1, 10, 100, 1000, 10000, 100000, 1000000}
+
+	for _, n := range filterSizes {
 		fmt.Printf("N: %d\t", n)
 		if n < 10000 {
 			fmt.Printf("\t")
 		}
-		for _, q := range []int{1, 10, 100, 1000, 10000, 100000, 1000000} {
+		for _, q := range querySizes {
 			mType, r := Optimize(q, n)
 			fmt.Printf("q:%d=%s (%.03f x)\t", q, mType, 1/r)
 		}
@@ -24,6 +30,58 @@ func TestOptimize(t *testing.T) {
 	}
 }
 
+// BenchmarkCostBlock exercises the MatchBlock workload that costBlock
+// models: build a query hash-set once (CostKey + CostInsert, done before the
+// timer starts, mirroring how BenchmarkCostRead builds its filter first),
+// then stream every entry out of an n-element filter, decoding (CostRead),
+// comparing the decoded value against the previous one (CostComp), and
+// probing it against the prebuilt set (CostLookup).
+func BenchmarkCostBlock(t *testing.B) {
+	const querySetSize = 1000
+
+	data := make([][]byte, t.N)
+	for i := range data {
+		data[i] = make([]byte, 24)
+	}
+
+	key := [KeySize]byte{
+		0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08,
+		0x09, 0x10, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16,
+	}
+
+	filter, _ := BuildGCSFilter(19, 784931, key, data)
+
+	filterData, _ := filter.Bytes()
+
+	querySet := make(map[uint64]struct{}, querySetSize)
+	for i := 0; i < querySetSize; i++ {
+		d := make([]byte, 24)
+		prand.Read(d)
+		querySet[siphash.Sum64(d, &key)] = struct{}{}
+	}
+
+	b := bstream.NewBStreamReader(filterData)
+
+	t.ReportAllocs()
+	t.ResetTimer()
+
+	var value, prev uint64
+	for i := uint32(0); i < filter.N(); i++ {
+		delta, err := filter.readFullUint64(b)
+		if err != nil {
+			return
+		}
+		value += delta
+
+		greater := value > prev
+		prev = value
+		_ = greater
+
+		_, ok = querySet[value]
+	}
+	_ = ok
+}
+
 func BenchmarkCostSort(t *testing.B) {
 	s := make([]uint64, t.N)
 	for i := range s {
@@ -137,6 +137,108 @@ func IsContextualSorted(tx *wire.MsgTx, ctxts [][]byte) bool {
 	return true
 }
 
+// InPlaceCommitSort modifies the passed transaction's inputs and outputs to
+// be sorted based on a _commitment_ BIP 69. Inputs are sorted exactly as with
+// BIP 69, and outputs are sorted as with BIP 69, using the output's CLTV
+// expiry as a final tie breaker. This mirrors the sort Lightning nodes apply
+// to commitment transactions, where HTLC outputs routinely share both value
+// and pkScript but differ in CLTV expiry.
+//
+// WARNING: This function must NOT be called with published transactions since
+// it will mutate the transaction if it's not already sorted.  This can cause
+// issues if you mutate a tx in a block, for example, which would invalidate the
+// block.  It could also cause cached hashes, such as in a btcutil.Tx to become
+// invalidated.
+//
+// The function should only be used if the caller is creating the transaction or
+// is otherwise 100% positive mutating will not cause adverse affects due to
+// other dependencies.
+func InPlaceCommitSort(tx *wire.MsgTx, cltvs []uint32) {
+	sort.Sort(sortableInputSlice(tx.TxIn))
+
+	commitOutputs := makeSortableOutputCommitSlice(tx.TxOut, cltvs)
+	sort.Sort(commitOutputs)
+	for i, c := range commitOutputs {
+		tx.TxOut[i] = c.TxOut
+	}
+}
+
+// CommitSort returns a new transaction with the inputs and outputs sorted
+// based on a _commitment_ BIP 69, as in InPlaceCommitSort. The passed
+// transaction is not modified and the new transaction might have a different
+// hash if any sorting was done.
+func CommitSort(tx *wire.MsgTx, cltvs []uint32) *wire.MsgTx {
+	txCopy := tx.Copy()
+	sort.Sort(sortableInputSlice(txCopy.TxIn))
+
+	commitOutputs := makeSortableOutputCommitSlice(txCopy.TxOut, cltvs)
+	sort.Sort(commitOutputs)
+	for i, c := range commitOutputs {
+		txCopy.TxOut[i] = c.TxOut
+	}
+
+	return txCopy
+}
+
+// IsCommitSorted checks whether tx has inputs and outputs sorted according to
+// a _commitment_ BIP 69 sort.
+func IsCommitSorted(tx *wire.MsgTx, cltvs []uint32) bool {
+	if !sort.IsSorted(sortableInputSlice(tx.TxIn)) {
+		return false
+	}
+	if !sort.IsSorted(makeSortableOutputCommitSlice(tx.TxOut, cltvs)) {
+		return false
+	}
+	return true
+}
+
+type txOutWithCLTV struct {
+	*wire.TxOut
+	CLTV uint32
+}
+
+// makeSortableOutputCommitSlice zips a slice of txouts with their CLTV
+// expiries so that the outputs can be sorted using a commitment BIP 69 sort.
+func makeSortableOutputCommitSlice(
+	txouts []*wire.TxOut, cltvs []uint32) sortableOutputCommitSlice {
+
+	if len(txouts) != len(cltvs) {
+		panic("length of txouts and cltvs must be equal")
+	}
+
+	// Create a txOutWithCLTV that marries each txout to its CLTV expiry.
+	commitOutputs := make(sortableOutputCommitSlice, len(txouts))
+	for i, txout := range txouts {
+		commitOutputs[i] = txOutWithCLTV{
+			TxOut: txout,
+			CLTV:  cltvs[i],
+		}
+	}
+
+	return commitOutputs
+}
+
+type sortableOutputCommitSlice []txOutWithCLTV
+
+// Implement the sort.Interface.
+func (s sortableOutputCommitSlice) Len() int      { return len(s) }
+func (s sortableOutputCommitSlice) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s sortableOutputCommitSlice) Less(i, j int) bool {
+	// If the values are distinct, return value_i < value_j.
+	if s[i].Value != s[j].Value {
+		return s[i].Value < s[j].Value
+	}
+
+	// If the pk scripts are distinct, return pkscript_i < pkscript_j.
+	pkScriptCmp := bytes.Compare(s[i].PkScript, s[j].PkScript)
+	if pkScriptCmp != 0 {
+		return pkScriptCmp == -1
+	}
+
+	// Otherwise, compare the CLTV expiries to determine if i < j.
+	return s[i].CLTV < s[j].CLTV
+}
+
 type txOutWithContext struct {
 	*wire.TxOut
 	Context []byte
@@ -0,0 +1,136 @@
+// Copyright (c) 2015-2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package txsort
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+// cltvCommitOutputs returns a slice of outputs representative of a Lightning
+// commitment transaction: a handful of HTLCs that intentionally collide on
+// both value and pkScript but differ in CLTV expiry, plus a couple of
+// non-HTLC outputs that are unique on pkScript alone.
+func cltvCommitOutputs() ([]*wire.TxOut, []uint32) {
+	htlcScript := []byte{0x00, 0x20, 0x01, 0x02, 0x03}
+
+	txouts := []*wire.TxOut{
+		{Value: 1000, PkScript: htlcScript},
+		{Value: 1000, PkScript: htlcScript},
+		{Value: 1000, PkScript: htlcScript},
+		{Value: 2000, PkScript: []byte{0x00, 0x14, 0xaa}},
+		{Value: 500, PkScript: []byte{0x00, 0x14, 0xbb}},
+	}
+	cltvs := []uint32{144, 100, 200, 0, 0}
+
+	return txouts, cltvs
+}
+
+// TestCommitSort checks that CommitSort and InPlaceCommitSort order outputs by
+// (value, pkScript, CLTV) against a known vector where three HTLC outputs
+// share both value and pkScript and must be tie-broken on CLTV expiry alone.
+func TestCommitSort(t *testing.T) {
+	txouts, cltvs := cltvCommitOutputs()
+
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.TxOut = txouts
+
+	sortedTx := CommitSort(tx, cltvs)
+
+	// CommitSort doesn't reorder cltvs, so check the sorted transaction
+	// against the CLTVs of its outputs in their sorted positions.
+	sortedCLTVs := []uint32{0, 100, 144, 200, 0}
+	if !IsCommitSorted(sortedTx, sortedCLTVs) {
+		t.Fatal("expected sorted transaction to report as sorted")
+	}
+
+	wantOrder := []int64{500, 1000, 1000, 1000, 2000}
+	for i, txout := range sortedTx.TxOut {
+		if txout.Value != wantOrder[i] {
+			t.Fatalf("output %d: want value %d, got %d", i,
+				wantOrder[i], txout.Value)
+		}
+	}
+
+	// The three colliding HTLC outputs must appear in ascending CLTV
+	// order since neither value nor pkScript distinguish them. CommitSort
+	// deep-copies the outputs, so they are told apart by pointer after an
+	// in-place sort over the original outputs instead.
+	inPlaceTx := wire.NewMsgTx(wire.TxVersion)
+	inPlaceTx.TxOut = append([]*wire.TxOut(nil), txouts...)
+	InPlaceCommitSort(inPlaceTx, cltvs)
+
+	wantCLTVs := []uint32{100, 144, 200}
+	for i, cltv := range wantCLTVs {
+		txout := inPlaceTx.TxOut[i+1]
+		idx := -1
+		for j, orig := range txouts {
+			if orig == txout {
+				idx = j
+			}
+		}
+		if idx == -1 || cltvs[idx] != cltv {
+			t.Fatalf("htlc %d: want cltv %d at sorted position %d",
+				i, cltv, i+1)
+		}
+	}
+
+	// The original transaction must be unmodified.
+	if tx.TxOut[0].Value != 1000 {
+		t.Fatal("CommitSort must not mutate the original transaction")
+	}
+}
+
+// TestInPlaceCommitSortPanicsOnMismatch checks that InPlaceCommitSort panics
+// when the CLTV slice isn't 1:1 with the transaction's outputs, mirroring the
+// behavior of InPlaceContextualSort.
+func TestInPlaceCommitSortPanicsOnMismatch(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic on cltv/txout length mismatch")
+		}
+	}()
+
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.TxOut = []*wire.TxOut{{Value: 1, PkScript: []byte{0x00}}}
+
+	InPlaceCommitSort(tx, []uint32{1, 2})
+}
+
+// TestCommitSortShuffle is a property test verifying that shuffling a
+// commitment transaction's outputs and re-sorting always yields the same
+// canonical order, regardless of the starting permutation.
+func TestCommitSortShuffle(t *testing.T) {
+	txouts, cltvs := cltvCommitOutputs()
+
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.TxOut = txouts
+	wantTx := CommitSort(tx, cltvs)
+
+	rng := rand.New(rand.NewSource(1))
+	for trial := 0; trial < 50; trial++ {
+		perm := rng.Perm(len(txouts))
+
+		shuffledOuts := make([]*wire.TxOut, len(txouts))
+		shuffledCLTVs := make([]uint32, len(cltvs))
+		for i, p := range perm {
+			shuffledOuts[i] = txouts[p]
+			shuffledCLTVs[i] = cltvs[p]
+		}
+
+		shuffledTx := wire.NewMsgTx(wire.TxVersion)
+		shuffledTx.TxOut = shuffledOuts
+
+		gotTx := CommitSort(shuffledTx, shuffledCLTVs)
+		for i, txout := range gotTx.TxOut {
+			if txout.Value != wantTx.TxOut[i].Value {
+				t.Fatalf("trial %d: output %d value mismatch "+
+					"after reshuffle", trial, i)
+			}
+		}
+	}
+}